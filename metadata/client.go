@@ -0,0 +1,255 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/xerrors"
+)
+
+// 各Keyにどれくらいの期間キャッシュするかを表すTTL
+// projectIDTTLが0の場合は、プロセスが生きている間キャッシュし続ける (ProjectIDはプロセスの生存期間中不変のため)
+const (
+	projectIDTTL           = 0
+	instanceIDTTL          = 0
+	serviceAccountEmailTTL = 1 * time.Hour
+	zoneTTL                = 1 * time.Hour
+	regionTTL              = 1 * time.Hour
+	instanceAttributeTTL   = 10 * time.Second
+	projectAttributeTTL    = 10 * time.Second
+)
+
+// Client is Metadata ServerへのアクセスをKeyごとのTTLでキャッシュし、同時に発生した同一Keyの問い合わせをsingleflightでまとめるクライアント
+// project idのようにプロセスの生存期間中不変な値まで毎回HTTPのRound Tripが発生していたのを防ぎ、request pathでの利用に耐えられるようにする
+type Client struct {
+	// HTTPClient is Metadata Serverへのアクセスに利用する http.Client。未設定の場合は http.DefaultClient を利用する
+	HTTPClient *http.Client
+
+	group singleflight.Group
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value   string
+	expires time.Time // expiresがzero valueの場合は、永続キャッシュであることを表す
+}
+
+// DefaultClient is パッケージレベルの関数(ProjectID, ServiceAccountEmailなど)が内部的に利用するデフォルトのClient
+var DefaultClient = NewClient()
+
+// NewClient is Clientを作成する
+func NewClient() *Client {
+	return &Client{
+		HTTPClient: http.DefaultClient,
+		cache:      map[string]cacheEntry{},
+	}
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) getCache(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.cache[key]
+	if !ok {
+		return "", false
+	}
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		delete(c.cache, key)
+		return "", false
+	}
+	return e.value, true
+}
+
+func (c *Client) setCache(key string, value string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+	c.cache[key] = cacheEntry{value: value, expires: expires}
+}
+
+// fetch is keyに対応する値をcacheから返し、cacheにない場合はsingleflightで同時リクエストをまとめつつfnを呼び出してcacheに詰める
+// singleflightでまとめられた呼び出しは、最初にDoChanを呼んだcallerだけでなく後から乗っかってきた全callerに結果を配る共有の処理なので、
+// 誰か1人のctxがcancelされたからといって他のcallerを巻き込んで失敗させてはいけない
+// そのため、共有本体のfnにはcontext.Background()を渡し、callerごとのctxのcancelはselectでそのcallerの待ち受けにのみ反映させる
+func (c *Client) fetch(ctx context.Context, key string, ttl time.Duration, fn func(ctx context.Context) (string, error)) (string, error) {
+	if v, ok := c.getCache(key); ok {
+		return v, nil
+	}
+
+	resultCh := c.group.DoChan(key, func() (interface{}, error) {
+		if v, ok := c.getCache(key); ok {
+			return v, nil
+		}
+		v, err := fn(context.Background())
+		if err != nil {
+			return "", err
+		}
+		c.setCache(key, v, ttl)
+		return v, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case res := <-resultCh:
+		if res.Err != nil {
+			return "", res.Err
+		}
+		return res.Val.(string), nil
+	}
+}
+
+// ProjectIDContext is ctxを受け取る以外はProjectIDと同じ。ctxがキャンセルされた場合は、結果を待たずにctx.Err()を返す
+// Metadata Serverへのリクエストはsingleflightで共有されているので中断されず、完了すれば結果はcacheに詰められる
+func (c *Client) ProjectIDContext(ctx context.Context) (string, error) {
+	return c.fetch(ctx, "project-id", projectIDTTL, func(ctx context.Context) (string, error) {
+		if !metadata.OnGCE() {
+			p := os.Getenv("GOOGLE_CLOUD_PROJECT")
+			if p != "" {
+				return p, nil
+			}
+			p = os.Getenv("GCLOUD_PROJECT")
+			if p != "" {
+				return p, nil
+			}
+			return "", NewErrNotFound("project id environment valiable is not found. plz set $GOOGLE_CLOUD_PROJECT", nil, nil)
+		}
+
+		projectID, err := metadata.NewClient(c.httpClient()).ProjectIDWithContext(ctx)
+		if err != nil {
+			return "", xerrors.Errorf("failed get project id from metadata server: %w", err)
+		}
+		if projectID == "" {
+			return "", NewErrNotFound("project id is not found", nil, nil)
+		}
+		return projectID, nil
+	})
+}
+
+// ServiceAccountEmailContext is ctxを受け取る以外はServiceAccountEmailと同じ
+func (c *Client) ServiceAccountEmailContext(ctx context.Context) (string, error) {
+	return c.fetch(ctx, "service-account-email", serviceAccountEmailTTL, func(ctx context.Context) (string, error) {
+		if !metadata.OnGCE() {
+			return os.Getenv("GCLOUD_SERVICE_ACCOUNT"), nil
+		}
+		sa, err := c.getMetadataContext(ctx, "service-accounts/default/email")
+		if err != nil {
+			return "", xerrors.Errorf("failed get ServiceAccountEmail : %w", err)
+		}
+		return string(sa), nil
+	})
+}
+
+// RegionContext is ctxを受け取る以外はRegionと同じ
+func (c *Client) RegionContext(ctx context.Context) (string, error) {
+	return c.fetch(ctx, "region", regionTTL, func(ctx context.Context) (string, error) {
+		if !metadata.OnGCE() {
+			return os.Getenv("INSTANCE_REGION"), nil
+		}
+		zone, err := c.getMetadataContext(ctx, "zone")
+		if err == nil {
+			return ExtractionRegion(string(zone))
+		}
+
+		region, rerr := c.getMetadataContext(ctx, "region")
+		if rerr != nil {
+			return "", xerrors.Errorf("failed get Region : %w", rerr)
+		}
+		return ExtractionRegionFromInstanceRegion(string(region))
+	})
+}
+
+// ZoneContext is ctxを受け取る以外はZoneと同じ
+func (c *Client) ZoneContext(ctx context.Context) (string, error) {
+	return c.fetch(ctx, "zone", zoneTTL, func(ctx context.Context) (string, error) {
+		if !metadata.OnGCE() {
+			return os.Getenv("INSTANCE_ZONE"), nil
+		}
+		zone, err := c.getMetadataContext(ctx, "zone")
+		if err != nil {
+			return "", xerrors.Errorf("failed get Zone : %w", err)
+		}
+		return ExtractionZone(string(zone))
+	})
+}
+
+// InstanceIDContext is ctxを受け取る以外はInstanceIDと同じ
+func (c *Client) InstanceIDContext(ctx context.Context) (string, error) {
+	return c.fetch(ctx, "instance-id", instanceIDTTL, func(ctx context.Context) (string, error) {
+		switch Runtime() {
+		case RuntimeKindGAEStandard, RuntimeKindGAEFlex:
+			return os.Getenv("GAE_INSTANCE"), nil
+		case RuntimeKindLocal:
+			return "", nil
+		default:
+			v, err := c.getMetadataContext(ctx, "id")
+			if err != nil {
+				return "", xerrors.Errorf("failed get InstanceID : %w", err)
+			}
+			return string(v), nil
+		}
+	})
+}
+
+// GetInstanceAttributeContext is ctxを受け取る以外はGetInstanceAttributeと同じ
+func (c *Client) GetInstanceAttributeContext(ctx context.Context, key string) (string, error) {
+	return c.fetch(ctx, "instance-attribute:"+key, instanceAttributeTTL, func(ctx context.Context) (string, error) {
+		if !metadata.OnGCE() {
+			return os.Getenv(fmt.Sprintf("INSTANCE_%s", key)), nil
+		}
+		return metadata.NewClient(c.httpClient()).InstanceAttributeValueWithContext(ctx, key)
+	})
+}
+
+// GetProjectAttributeContext is ctxを受け取る以外はGetProjectAttributeと同じ
+func (c *Client) GetProjectAttributeContext(ctx context.Context, key string) (string, error) {
+	return c.fetch(ctx, "project-attribute:"+key, projectAttributeTTL, func(ctx context.Context) (string, error) {
+		if !metadata.OnGCE() {
+			return os.Getenv(fmt.Sprintf("PROJECT_%s", key)), nil
+		}
+		return metadata.NewClient(c.httpClient()).ProjectAttributeValueWithContext(ctx, key)
+	})
+}
+
+// getMetadataContext is ctxとClientの持つhttp.Clientを使って、Metadata Serverのinstance/配下のpathを取得する
+func (c *Client) getMetadataContext(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://metadata.google.internal/computeMetadata/v1/instance/%s", path), nil)
+	if err != nil {
+		return nil, xerrors.Errorf("failed http.NewRequest. path=%s : %w", path, err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	res, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, xerrors.Errorf("failed http.SendReq. path=%s : %w", path, err)
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, xerrors.Errorf("failed read response.Body. path=%s : %w", path, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, xerrors.Errorf("metadata server response is %v:%v", res.StatusCode, string(b))
+	}
+
+	return b, nil
+}
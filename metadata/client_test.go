@@ -0,0 +1,118 @@
+package metadata
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientFetchCachesResult(t *testing.T) {
+	c := NewClient()
+	var calls int32
+
+	fn := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "value", nil
+	}
+
+	for i := 0; i < 3; i++ {
+		v, err := c.fetch(context.Background(), "key", time.Hour, fn)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if v != "value" {
+			t.Fatalf("v = %q, want %q", v, "value")
+		}
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("fn was called %d times, want 1", got)
+	}
+}
+
+func TestClientFetchSingleflightDeduplicatesConcurrentCalls(t *testing.T) {
+	c := NewClient()
+	var calls int32
+	release := make(chan struct{})
+
+	fn := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "value", nil
+	}
+
+	const n = 5
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			v, err := c.fetch(context.Background(), "key", time.Hour, fn)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if v != "value" {
+				t.Errorf("v = %q, want %q", v, "value")
+			}
+		}()
+	}
+
+	// goroutineが全員singleflightで待ち合わせているはずのタイミングでfnを解放する
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("fn was called %d times, want 1", got)
+	}
+}
+
+// TestClientFetchContextCancellationDoesNotAffectOtherCallers is
+// singleflightで共有されている1callerのctxがcancelされても、他のcallerには影響しないことを確認する
+func TestClientFetchContextCancellationDoesNotAffectOtherCallers(t *testing.T) {
+	c := NewClient()
+	var calls int32
+	release := make(chan struct{})
+
+	fn := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "value", nil
+	}
+
+	cancelCtx, cancel := context.WithCancel(context.Background())
+
+	var canceledErr, okErr error
+	var okValue string
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, canceledErr = c.fetch(cancelCtx, "key", time.Hour, fn)
+	}()
+	go func() {
+		defer wg.Done()
+		okValue, okErr = c.fetch(context.Background(), "key", time.Hour, fn)
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	cancel()
+	time.Sleep(30 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if canceledErr == nil {
+		t.Fatal("expected the canceled caller to get an error")
+	}
+	if okErr != nil {
+		t.Fatalf("unexpected error for the still-valid caller: %v", okErr)
+	}
+	if okValue != "value" {
+		t.Fatalf("okValue = %q, want %q", okValue, "value")
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("fn was called %d times, want 1 (singleflight should dedupe)", got)
+	}
+}
@@ -1,14 +1,9 @@
 package metadata
 
 import (
+	"context"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"os"
 	"strings"
-
-	"cloud.google.com/go/compute/metadata"
-	"golang.org/x/xerrors"
 )
 
 // OnGCP is GCP上で動いているかどうかを返す
@@ -16,46 +11,34 @@ import (
 // Google App Engine Standard for Go 1.11
 // Google Compute Engine
 // Google Kubernetes Engine
+// Cloud Run
+// Cloud Functions (1st gen/2nd gen)
 func OnGCP() bool {
-	return metadata.OnGCE()
+	return Runtime() != RuntimeKindLocal
 }
 
 // ProjectID is Return current GCP ProjectID
 // GCP上で動いている場合は、Project Metadataから取得し、そうでなければ、環境変数から取得する
+// 内部ではDefaultClientのキャッシュを経由するので、2回目以降の呼び出しではMetadata Serverへのリクエストは発生しない
 func ProjectID() (string, error) {
-	if !metadata.OnGCE() {
-		p := os.Getenv("GOOGLE_CLOUD_PROJECT")
-		if p != "" {
-			return p, nil
-		}
-		p = os.Getenv("GCLOUD_PROJECT")
-		if p != "" {
-			return p, nil
-		}
-		return "", NewErrNotFound("project id environment valiable is not found. plz set $GOOGLE_CLOUD_PROJECT", nil, nil)
-	}
+	return DefaultClient.ProjectIDContext(context.Background())
+}
 
-	projectID, err := metadata.ProjectID()
-	if err != nil {
-		return "", xerrors.Errorf("failed get project id from metadata server: %w", err)
-	}
-	if projectID == "" {
-		return "", NewErrNotFound("project id is not found", nil, nil)
-	}
-	return projectID, nil
+// ProjectIDContext is ctxを受け取る以外はProjectIDと同じ
+func ProjectIDContext(ctx context.Context) (string, error) {
+	return DefaultClient.ProjectIDContext(ctx)
 }
 
 // ServiceAccountEmail is Return current Service Account Email
 // GCP上で動いている場合は、Metadataから取得し、そうでなければ、環境変数から取得する
+// 内部ではDefaultClientのキャッシュを経由するので、TTLの間はMetadata Serverへのリクエストは発生しない
 func ServiceAccountEmail() (string, error) {
-	if !metadata.OnGCE() {
-		return os.Getenv("GCLOUD_SERVICE_ACCOUNT"), nil
-	}
-	sa, err := getMetadata("service-accounts/default/email")
-	if err != nil {
-		return "", xerrors.Errorf("failed get ServiceAccountEmail : %w", err)
-	}
-	return string(sa), nil
+	return DefaultClient.ServiceAccountEmailContext(context.Background())
+}
+
+// ServiceAccountEmailContext is ctxを受け取る以外はServiceAccountEmailと同じ
+func ServiceAccountEmailContext(ctx context.Context) (string, error) {
+	return DefaultClient.ServiceAccountEmailContext(ctx)
 }
 
 // ServiceAccountName is Return current Service Account Name
@@ -88,29 +71,26 @@ func ServiceAccountID() (string, error) {
 }
 
 // Region is Appが動いているRegionを取得する
+// Cloud Run/Cloud Functions/GAEなど、instance/zoneが存在しないランタイムの場合は、instance/regionから取得する
+// 内部ではDefaultClientのキャッシュを経由するので、TTLの間はMetadata Serverへのリクエストは発生しない
 func Region() (string, error) {
-	if !metadata.OnGCE() {
-		return os.Getenv("INSTANCE_REGION"), nil
-	}
-	zone, err := getMetadata("zone")
-	if err != nil {
-		return "", xerrors.Errorf("failed get Zone : %w", err)
-	}
+	return DefaultClient.RegionContext(context.Background())
+}
 
-	return ExtractionRegion(string(zone))
+// RegionContext is ctxを受け取る以外はRegionと同じ
+func RegionContext(ctx context.Context) (string, error) {
+	return DefaultClient.RegionContext(ctx)
 }
 
 // Zone is Appが動いているZoneを取得する
+// 内部ではDefaultClientのキャッシュを経由するので、TTLの間はMetadata Serverへのリクエストは発生しない
 func Zone() (string, error) {
-	if !metadata.OnGCE() {
-		return os.Getenv("INSTANCE_ZONE"), nil
-	}
-	zone, err := getMetadata("zone")
-	if err != nil {
-		return "", xerrors.Errorf("failed get Zone : %w", err)
-	}
+	return DefaultClient.ZoneContext(context.Background())
+}
 
-	return ExtractionZone(string(zone))
+// ZoneContext is ctxを受け取る以外はZoneと同じ
+func ZoneContext(ctx context.Context) (string, error) {
+	return DefaultClient.ZoneContext(ctx)
 }
 
 // ExtractionRegion is Metadata Serverから取得する projects/[NUMERIC_PROJECT_ID]/zones/[ZONE] 形式の文字列から、Region部分を取り出す
@@ -138,49 +118,24 @@ func ExtractionZone(metaZone string) (string, error) {
 
 // GetInstanceAttribute is Instance Metadataを取得する
 // GCP以外で動いている時は、環境変数を取得する
+// 内部ではDefaultClientのキャッシュを経由するが、Instance Attributeは変わりうる値なので短いTTLでキャッシュする
 func GetInstanceAttribute(key string) (string, error) {
-	if !metadata.OnGCE() {
-		return os.Getenv(fmt.Sprintf("INSTANCE_%s", key)), nil
-	}
+	return DefaultClient.GetInstanceAttributeContext(context.Background(), key)
+}
 
-	v, err := metadata.InstanceAttributeValue(key)
-	if err != nil {
-		return "", err
-	}
-	return v, nil
+// GetInstanceAttributeContext is ctxを受け取る以外はGetInstanceAttributeと同じ
+func GetInstanceAttributeContext(ctx context.Context, key string) (string, error) {
+	return DefaultClient.GetInstanceAttributeContext(ctx, key)
 }
 
 // GetProjectAttribute is Project Metadataを取得する
 // GCP以外で動いている時は、環境変数を取得する
+// 内部ではDefaultClientのキャッシュを経由するが、Project Attributeは変わりうる値なので短いTTLでキャッシュする
 func GetProjectAttribute(key string) (string, error) {
-	if !metadata.OnGCE() {
-		return os.Getenv(fmt.Sprintf("PROJECT_%s", key)), nil
-	}
-
-	v, err := metadata.ProjectAttributeValue(key)
-	if err != nil {
-		return "", err
-	}
-	return v, nil
+	return DefaultClient.GetProjectAttributeContext(context.Background(), key)
 }
 
-func getMetadata(path string) ([]byte, error) {
-	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://metadata.google.internal/computeMetadata/v1/instance/%s", path), nil)
-	if err != nil {
-		return nil, xerrors.Errorf("failed http.NewRequest. path=%s : %w", path, err)
-	}
-	req.Header.Set("Metadata-Flavor", "Google")
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, xerrors.Errorf("failed http.SendReq. path=%s : %w", path, err)
-	}
-	b, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return nil, xerrors.Errorf("failed read response.Body. path=%s : %w", path, err)
-	}
-	if res.StatusCode != http.StatusOK {
-		return nil, xerrors.Errorf("metadata server response is %v:%v", res.StatusCode, string(b))
-	}
-
-	return b, nil
+// GetProjectAttributeContext is ctxを受け取る以外はGetProjectAttributeと同じ
+func GetProjectAttributeContext(ctx context.Context, key string) (string, error) {
+	return DefaultClient.GetProjectAttributeContext(ctx, key)
 }
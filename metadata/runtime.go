@@ -0,0 +1,130 @@
+package metadata
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"cloud.google.com/go/compute/metadata"
+)
+
+// RuntimeKind is 現在のプロセスが動いているGCPのランタイムの種類を表す
+type RuntimeKind int
+
+const (
+	// RuntimeKindLocal is GCP以外(開発者のPCなど)で動いている
+	RuntimeKindLocal RuntimeKind = iota
+	// RuntimeKindGCE is Google Compute Engineで動いている
+	RuntimeKindGCE
+	// RuntimeKindGKE is Google Kubernetes Engineで動いている
+	RuntimeKindGKE
+	// RuntimeKindCloudRun is Cloud Runで動いている
+	RuntimeKindCloudRun
+	// RuntimeKindCloudFunctions is Cloud Functions (1st gen)で動いている
+	// Cloud Functions 2nd genはK_SERVICEがセットされるので、RuntimeKindCloudRunとして判定される
+	RuntimeKindCloudFunctions
+	// RuntimeKindGAEStandard is Google App Engine Standardで動いている
+	RuntimeKindGAEStandard
+	// RuntimeKindGAEFlex is Google App Engine Flexible Environmentで動いている
+	RuntimeKindGAEFlex
+)
+
+// String is RuntimeKindを人が読める文字列に変換する
+func (k RuntimeKind) String() string {
+	switch k {
+	case RuntimeKindGCE:
+		return "GCE"
+	case RuntimeKindGKE:
+		return "GKE"
+	case RuntimeKindCloudRun:
+		return "CloudRun"
+	case RuntimeKindCloudFunctions:
+		return "CloudFunctions"
+	case RuntimeKindGAEStandard:
+		return "GAEStandard"
+	case RuntimeKindGAEFlex:
+		return "GAEFlex"
+	default:
+		return "Local"
+	}
+}
+
+// Runtime is 現在のプロセスがGCPのどのランタイム上で動いているかを判定する
+// Cloud Run/Cloud Functions/GAEは、metadata serverの形だけではなく環境変数の有無も併用して判定する
+func Runtime() RuntimeKind {
+	if os.Getenv("GAE_SERVICE") != "" {
+		if strings.HasPrefix(os.Getenv("GAE_ENV"), "flex") {
+			return RuntimeKindGAEFlex
+		}
+		return RuntimeKindGAEStandard
+	}
+	if os.Getenv("K_SERVICE") != "" {
+		// Cloud Functions 2nd genはCloud Run上で動いているので、K_SERVICEとFUNCTION_TARGETの両方がセットされる
+		return RuntimeKindCloudRun
+	}
+	if os.Getenv("FUNCTION_TARGET") != "" {
+		return RuntimeKindCloudFunctions
+	}
+	if !metadata.OnGCE() {
+		return RuntimeKindLocal
+	}
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return RuntimeKindGKE
+	}
+	return RuntimeKindGCE
+}
+
+// ServiceName is Cloud RunのService名、Cloud Functionsの関数名、GAEのService名を返す
+// 該当するランタイムでない場合は、空文字を返す
+func ServiceName() string {
+	switch Runtime() {
+	case RuntimeKindCloudRun:
+		return os.Getenv("K_SERVICE")
+	case RuntimeKindCloudFunctions:
+		return os.Getenv("FUNCTION_TARGET")
+	case RuntimeKindGAEStandard, RuntimeKindGAEFlex:
+		return os.Getenv("GAE_SERVICE")
+	default:
+		return ""
+	}
+}
+
+// RevisionName is Cloud RunのRevision名、GAEのVersion名を返す
+// 該当するランタイムでない場合は、空文字を返す
+// Cloud Functions (1st gen)は、K_REVISIONに相当するRevisionの概念を公開していないため、常に空文字を返す
+func RevisionName() string {
+	switch Runtime() {
+	case RuntimeKindCloudRun:
+		return os.Getenv("K_REVISION")
+	case RuntimeKindGAEStandard, RuntimeKindGAEFlex:
+		return os.Getenv("GAE_VERSION")
+	default:
+		return ""
+	}
+}
+
+// InstanceID is 現在のプロセスが動いているInstanceのIDを返す
+// GAEはGAE_INSTANCE環境変数から、GCP以外(RuntimeKindLocal)では空文字を、それ以外はMetadata Serverのinstance/idから取得する
+// 内部ではDefaultClientのキャッシュを経由するので、2回目以降の呼び出しではMetadata Serverへのリクエストは発生しない
+func InstanceID() (string, error) {
+	return DefaultClient.InstanceIDContext(context.Background())
+}
+
+// InstanceIDContext is ctxを受け取る以外はInstanceIDと同じ
+func InstanceIDContext(ctx context.Context) (string, error) {
+	return DefaultClient.InstanceIDContext(ctx)
+}
+
+// ExtractionRegionFromInstanceRegion is Metadata Serverのinstance/regionから取得する projects/[NUMERIC_PROJECT_ID]/regions/[REGION] 形式の文字列から、Region部分を取り出す
+// Cloud Run/Cloud Functions/GAEなど、instance/zoneが取得できないランタイムのRegion()から利用する
+func ExtractionRegionFromInstanceRegion(instanceRegion string) (string, error) {
+	l := strings.Split(instanceRegion, "/")
+	if len(l) < 1 {
+		return "", NewErrInvalidArgument("required format : projects/[NUMERIC_PROJECT_ID]/regions/[REGION]", map[string]interface{}{"input_argument": instanceRegion}, nil)
+	}
+	v := l[len(l)-1]
+	if v == "" {
+		return "", NewErrInvalidArgument("required format : projects/[NUMERIC_PROJECT_ID]/regions/[REGION]", map[string]interface{}{"input_argument": instanceRegion}, nil)
+	}
+	return v, nil
+}
@@ -0,0 +1,107 @@
+package metadata
+
+import (
+	"os"
+	"testing"
+)
+
+func TestRuntime(t *testing.T) {
+	envKeys := []string{"GAE_SERVICE", "GAE_ENV", "K_SERVICE", "FUNCTION_TARGET", "KUBERNETES_SERVICE_HOST"}
+	reset := func() {
+		for _, k := range envKeys {
+			os.Unsetenv(k)
+		}
+	}
+
+	cases := []struct {
+		name string
+		env  map[string]string
+		want RuntimeKind
+	}{
+		{
+			name: "GAE Standard",
+			env:  map[string]string{"GAE_SERVICE": "default"},
+			want: RuntimeKindGAEStandard,
+		},
+		{
+			name: "GAE Flex",
+			env:  map[string]string{"GAE_SERVICE": "default", "GAE_ENV": "flex"},
+			want: RuntimeKindGAEFlex,
+		},
+		{
+			name: "Cloud Run",
+			env:  map[string]string{"K_SERVICE": "my-service"},
+			want: RuntimeKindCloudRun,
+		},
+		{
+			// Cloud Functions (2nd gen)はCloud Run上で動いているので、K_SERVICEがセットされCloudRunとして判定される
+			name: "Cloud Functions 2nd gen is reported as CloudRun",
+			env:  map[string]string{"K_SERVICE": "my-function", "FUNCTION_TARGET": "HelloWorld"},
+			want: RuntimeKindCloudRun,
+		},
+		{
+			name: "Cloud Functions 1st gen",
+			env:  map[string]string{"FUNCTION_TARGET": "HelloWorld"},
+			want: RuntimeKindCloudFunctions,
+		},
+		{
+			name: "Local",
+			env:  map[string]string{},
+			want: RuntimeKindLocal,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			reset()
+			defer reset()
+			for k, v := range tt.env {
+				if err := os.Setenv(k, v); err != nil {
+					t.Fatalf("failed os.Setenv(%s) : %v", k, err)
+				}
+			}
+
+			if got := Runtime(); got != tt.want {
+				t.Errorf("Runtime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractionRegionFromInstanceRegion(t *testing.T) {
+	cases := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "valid",
+			input: "projects/123456789/regions/asia-northeast1",
+			want:  "asia-northeast1",
+		},
+		{
+			name:    "trailing slash with no region",
+			input:   "projects/123456789/regions/",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractionRegionFromInstanceRegion(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ExtractionRegionFromInstanceRegion(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"golang.org/x/xerrors"
+	"google.golang.org/api/idtoken"
+)
+
+// idTokenConfig is IDTokenの挙動を保持する
+type idTokenConfig struct {
+	format string
+}
+
+// IDTokenOption is IDTokenの挙動を変更するオプション
+type IDTokenOption func(*idTokenConfig)
+
+// IDTokenFormatFull is audience claim以外に、Compute Engineのインスタンス情報などを含んだJWTを要求する
+// 指定しない場合は "standard" format (audience claimのみ) のJWTが返ってくる
+func IDTokenFormatFull() IDTokenOption {
+	return func(c *idTokenConfig) {
+		c.format = "full"
+	}
+}
+
+// IDToken is audience向けのOIDC ID Tokenを取得する
+// GCP上で動いている場合は、Metadata Serverのservice-accounts/default/identityから取得し、そうでない場合は、idtoken.NewTokenSourceから取得する
+// Cloud Run、Cloud Functions、IAPで保護されたエンドポイントなど、サービス間呼び出しの認証に利用する
+func IDToken(ctx context.Context, audience string, opts ...IDTokenOption) (string, error) {
+	c := &idTokenConfig{format: "standard"}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if !metadata.OnGCE() {
+		ts, err := idtoken.NewTokenSource(ctx, audience)
+		if err != nil {
+			return "", xerrors.Errorf("failed idtoken.NewTokenSource audience=%s : %w", audience, err)
+		}
+		tok, err := ts.Token()
+		if err != nil {
+			return "", xerrors.Errorf("failed get IDToken from TokenSource audience=%s : %w", audience, err)
+		}
+		return tok.AccessToken, nil
+	}
+
+	v := url.Values{}
+	v.Set("audience", audience)
+	v.Set("format", c.format)
+	b, err := DefaultClient.getMetadataContext(ctx, "service-accounts/default/identity?"+v.Encode())
+	if err != nil {
+		return "", xerrors.Errorf("failed get IDToken audience=%s : %w", audience, err)
+	}
+
+	return string(b), nil
+}
+
+// accessTokenResponse is Metadata Serverのservice-accounts/default/tokenのレスポンス
+type accessTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+	TokenType   string `json:"token_type"`
+}
+
+// AccessToken is scopesを指定して、現在のService AccountのOAuth2 Access Tokenを取得する
+// GCP上で動いている場合は、Metadata Serverのservice-accounts/default/tokenから取得し、そうでない場合は、google.DefaultTokenSourceから取得する
+func AccessToken(ctx context.Context, scopes ...string) (*oauth2.Token, error) {
+	if !metadata.OnGCE() {
+		ts, err := google.DefaultTokenSource(ctx, scopes...)
+		if err != nil {
+			return nil, xerrors.Errorf("failed google.DefaultTokenSource: %w", err)
+		}
+		tok, err := ts.Token()
+		if err != nil {
+			return nil, xerrors.Errorf("failed get AccessToken from TokenSource: %w", err)
+		}
+		return tok, nil
+	}
+
+	path := "service-accounts/default/token"
+	if len(scopes) > 0 {
+		v := url.Values{}
+		v.Set("scopes", strings.Join(scopes, ","))
+		path += "?" + v.Encode()
+	}
+
+	b, err := DefaultClient.getMetadataContext(ctx, path)
+	if err != nil {
+		return nil, xerrors.Errorf("failed get AccessToken : %w", err)
+	}
+
+	var res accessTokenResponse
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, xerrors.Errorf("failed json.Unmarshal AccessToken response: %w", err)
+	}
+
+	return &oauth2.Token{
+		AccessToken: res.AccessToken,
+		TokenType:   res.TokenType,
+		Expiry:      time.Now().Add(time.Duration(res.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// IDTokenRoundTripper is リクエストごとにAudience向けの新しいID TokenをAuthorization Headerに詰めるhttp.RoundTripper
+// Cloud Run上の別サービスなど、サービス間呼び出しでID Tokenを都度付与したい場合にhttp.Clientのtransportとして利用する
+type IDTokenRoundTripper struct {
+	// Base is 実際にリクエストを送るRoundTripper。未設定の場合はhttp.DefaultTransportを利用する
+	Base http.RoundTripper
+	// Audience is IDTokenに渡すaudience
+	Audience string
+	// Options is IDTokenに渡すIDTokenOption
+	Options []IDTokenOption
+}
+
+// NewIDTokenRoundTripper is IDTokenRoundTripperを作成する
+// baseがnilの場合は、http.DefaultTransportを利用する
+func NewIDTokenRoundTripper(base http.RoundTripper, audience string, opts ...IDTokenOption) *IDTokenRoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &IDTokenRoundTripper{
+		Base:     base,
+		Audience: audience,
+		Options:  opts,
+	}
+}
+
+// RoundTrip is リクエストごとにIDTokenを取得し、Authorization: Bearer Headerを詰めてBaseに委譲する
+func (t *IDTokenRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	tok, err := IDToken(req.Context(), t.Audience, t.Options...)
+	if err != nil {
+		return nil, xerrors.Errorf("failed get IDToken for audience=%s : %w", t.Audience, err)
+	}
+
+	req2 := req.Clone(req.Context())
+	req2.Header.Set("Authorization", "Bearer "+tok)
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req2)
+}
@@ -117,6 +117,8 @@ var queryStatsBigQueryTableSchema = bigquery.Schema{
 	{Name: "AvgCPUSeconds", Required: true, Type: bigquery.FloatFieldType},
 }
 
+// ToBigQuery is QueryStatをbigquery.Inserter().Put (tabledata.insertAll)を使ってBigQueryに送る
+// Deprecated: tabledata.insertAllは1行あたりのQuotaがあり、InsertIDによる重複排除もbest-effortなため、代わりにToBigQueryStorageを利用する
 func (s *QueryStatsCopyService) ToBigQuery(ctx context.Context, dataset *bigquery.Dataset, table string, qss []*QueryStat) error {
 	var sss []*bigquery.StructSaver
 	for _, qs := range qss {
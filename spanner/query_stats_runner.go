@@ -0,0 +1,155 @@
+package spanner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+)
+
+// QueryStatsGranularity is spanner_sys.query_stats_top_*のどのGranularityを扱うかを表す
+type QueryStatsGranularity int
+
+const (
+	// QueryStatsGranularityMinute is spanner_sys.query_stats_top_minuteを扱う
+	QueryStatsGranularityMinute QueryStatsGranularity = iota
+	// QueryStatsGranularity10Minute is spanner_sys.query_stats_top_10minuteを扱う
+	QueryStatsGranularity10Minute
+	// QueryStatsGranularityHour is spanner_sys.query_stats_top_hourを扱う
+	QueryStatsGranularityHour
+)
+
+// String is QueryStatsGranularityを人が読める文字列に変換する
+func (g QueryStatsGranularity) String() string {
+	switch g {
+	case QueryStatsGranularity10Minute:
+		return "10minute"
+	case QueryStatsGranularityHour:
+		return "hour"
+	default:
+		return "minute"
+	}
+}
+
+// table is QueryStatsGranularityに対応するspanner_sysのTable名を返す
+func (g QueryStatsGranularity) table() string {
+	switch g {
+	case QueryStatsGranularity10Minute:
+		return queryStatsTop10MinuteTable
+	case QueryStatsGranularityHour:
+		return queryStatsTopHourTable
+	default:
+		return queryStatsTopMinuteTable
+	}
+}
+
+// QueryStatsCopyRunner is watermarkを使って、前回コピーした位置からのQueryStatのみをBigQueryにコピーする
+// QueryStatsCopyService.GetQueryStatsは毎回その時点のTopの結果を返すだけで、どこまでコピー済みかはCaller側で管理する必要があったため、
+// そのカーソル管理を肩代わりする
+type QueryStatsCopyRunner struct {
+	service     *QueryStatsCopyService
+	watermark   WatermarkStore
+	dataset     *bigquery.Dataset
+	bqTable     string
+	granularity QueryStatsGranularity
+}
+
+// QueryStatsCopyRunnerOption is QueryStatsCopyRunnerのオプションを設定する
+type QueryStatsCopyRunnerOption func(*QueryStatsCopyRunner)
+
+// WithGranularity is コピー対象のGranularityを指定する。指定しない場合は、QueryStatsGranularityMinuteになる
+func WithGranularity(granularity QueryStatsGranularity) QueryStatsCopyRunnerOption {
+	return func(r *QueryStatsCopyRunner) {
+		r.granularity = granularity
+	}
+}
+
+// NewQueryStatsCopyRunner is QueryStatsCopyRunnerを作成する
+func NewQueryStatsCopyRunner(service *QueryStatsCopyService, watermark WatermarkStore, dataset *bigquery.Dataset, bqTable string, opts ...QueryStatsCopyRunnerOption) *QueryStatsCopyRunner {
+	r := &QueryStatsCopyRunner{
+		service:     service,
+		watermark:   watermark,
+		dataset:     dataset,
+		bqTable:     bqTable,
+		granularity: QueryStatsGranularityMinute,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// watermarkKey is このRunnerが対象にしているSpanner Database + Granularity を一意に表すwatermarkのKeyを返す
+func (r *QueryStatsCopyRunner) watermarkKey() string {
+	return fmt.Sprintf("%s/%s", r.service.spanner.DatabaseName(), r.granularity)
+}
+
+// Tick is watermarkより新しいQueryStatのみをBigQueryにコピーし、watermarkを最新のIntervalEndまで進める
+// 1件もコピー対象がない場合は、何もせずに終了する
+func (r *QueryStatsCopyRunner) Tick(ctx context.Context) error {
+	key := r.watermarkKey()
+	watermark, err := r.watermark.Get(ctx, key)
+	if err != nil {
+		return errors.Wrapf(err, "failed get watermark key=%s", key)
+	}
+
+	qss, err := r.service.GetQueryStats(ctx, r.granularity.table())
+	if err != nil {
+		return errors.Wrap(err, "failed GetQueryStats")
+	}
+
+	news, newWatermark := filterNewQueryStats(qss, watermark)
+	if len(news) < 1 {
+		return nil
+	}
+
+	// ToBigQueryStorageがnilを返すのは、rowsがBigQueryにdurableにcommitされた場合のみという前提に依存している
+	// もしここがbest-effortな成功(書き込みが確認されていないのにnilを返す)に変わると、次のGetQueryStatsの結果ウィンドウから
+	// 該当するintervalが外れてしまい、watermarkだけが進んでrowsが永久に失われる
+	if err := r.service.ToBigQueryStorage(ctx, r.dataset, r.bqTable, news); err != nil {
+		return errors.Wrap(err, "failed ToBigQueryStorage")
+	}
+
+	if err := r.watermark.Set(ctx, key, newWatermark); err != nil {
+		return errors.Wrapf(err, "failed set watermark key=%s", key)
+	}
+
+	return nil
+}
+
+// filterNewQueryStats is qssからwatermarkより新しいIntervalEndを持つものだけを抽出し、コピー対象の新しいwatermarkとあわせて返す
+// 1件もなかった場合は、newWatermarkに引数のwatermarkをそのまま返す
+func filterNewQueryStats(qss []*QueryStat, watermark time.Time) (news []*QueryStat, newWatermark time.Time) {
+	newWatermark = watermark
+	for _, qs := range qss {
+		if !qs.IntervalEnd.After(watermark) {
+			continue
+		}
+		news = append(news, qs)
+		if qs.IntervalEnd.After(newWatermark) {
+			newWatermark = qs.IntervalEnd
+		}
+	}
+	return news, newWatermark
+}
+
+// Run is intervalごとにTickを実行し続ける
+// Cloud Run/Cloud Schedulerからキックされるpodのmain loopとして利用することを想定している
+// ctxがキャンセルされるか、Tickがエラーを返した場合に処理を終了する
+func (r *QueryStatsCopyRunner) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := r.Tick(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
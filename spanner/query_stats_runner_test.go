@@ -0,0 +1,99 @@
+package spanner
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeWatermarkStore is テスト用のインメモリWatermarkStore実装
+type fakeWatermarkStore struct {
+	mu    sync.Mutex
+	store map[string]time.Time
+}
+
+func newFakeWatermarkStore() *fakeWatermarkStore {
+	return &fakeWatermarkStore{store: map[string]time.Time{}}
+}
+
+func (f *fakeWatermarkStore) Get(ctx context.Context, key string) (time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.store[key], nil
+}
+
+func (f *fakeWatermarkStore) Set(ctx context.Context, key string, watermark time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.store[key] = watermark
+	return nil
+}
+
+func TestFakeWatermarkStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeWatermarkStore()
+
+	got, err := store.Get(ctx, "key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.IsZero() {
+		t.Fatalf("Get() on unseen key = %v, want zero value", got)
+	}
+
+	want := time.Date(2026, 7, 27, 0, 0, 0, 0, time.UTC)
+	if err := store.Set(ctx, "key", want); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err = store.Get(ctx, "key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.Equal(want) {
+		t.Errorf("Get() = %v, want %v", got, want)
+	}
+}
+
+func TestFilterNewQueryStats(t *testing.T) {
+	base := time.Date(2026, 7, 27, 0, 0, 0, 0, time.UTC)
+	qss := []*QueryStat{
+		{IntervalEnd: base.Add(-1 * time.Minute)}, // copied済み
+		{IntervalEnd: base},                       // watermarkと同じ = copied済み
+		{IntervalEnd: base.Add(1 * time.Minute)},
+		{IntervalEnd: base.Add(2 * time.Minute)},
+	}
+
+	news, newWatermark := filterNewQueryStats(qss, base)
+
+	if len(news) != 2 {
+		t.Fatalf("len(news) = %d, want 2", len(news))
+	}
+	if !news[0].IntervalEnd.Equal(base.Add(1 * time.Minute)) {
+		t.Errorf("news[0].IntervalEnd = %v, want %v", news[0].IntervalEnd, base.Add(1*time.Minute))
+	}
+	if !news[1].IntervalEnd.Equal(base.Add(2 * time.Minute)) {
+		t.Errorf("news[1].IntervalEnd = %v, want %v", news[1].IntervalEnd, base.Add(2*time.Minute))
+	}
+	want := base.Add(2 * time.Minute)
+	if !newWatermark.Equal(want) {
+		t.Errorf("newWatermark = %v, want %v", newWatermark, want)
+	}
+}
+
+func TestFilterNewQueryStatsNoNewRows(t *testing.T) {
+	base := time.Date(2026, 7, 27, 0, 0, 0, 0, time.UTC)
+	qss := []*QueryStat{
+		{IntervalEnd: base},
+		{IntervalEnd: base.Add(-1 * time.Minute)},
+	}
+
+	news, newWatermark := filterNewQueryStats(qss, base)
+
+	if len(news) != 0 {
+		t.Fatalf("len(news) = %d, want 0", len(news))
+	}
+	if !newWatermark.Equal(base) {
+		t.Errorf("newWatermark = %v, want unchanged %v", newWatermark, base)
+	}
+}
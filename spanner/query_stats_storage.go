@@ -0,0 +1,105 @@
+package spanner
+
+import (
+	"context"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/bigquery/storage/managedwriter"
+	"cloud.google.com/go/bigquery/storage/managedwriter/adapt"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ToBigQueryStorage is QueryStatをBigQuery Storage Write APIを使ってBigQueryに送る
+// bigquery.Inserter().Put (tabledata.insertAll)は、1行あたりのQuotaがあり、InsertIDによる重複排除もbest-effortで、今後Deprecatedになっていくため、
+// 新しく使う場合はこちらを利用する
+// DefaultStreamにOffsetを指定せずにAppendRowsするため、書き込みはat-least-onceになる
+// InsertIDも送らないので、同じQueryStat (IntervalEnd + TextFingerprint) を2回渡すと、BigQueryには2行入る
+func (s *QueryStatsCopyService) ToBigQueryStorage(ctx context.Context, dataset *bigquery.Dataset, table string, qss []*QueryStat) error {
+	if len(qss) < 1 {
+		return nil
+	}
+
+	client, err := managedwriter.NewClient(ctx, dataset.ProjectID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer client.Close()
+
+	storageSchema, err := adapt.BQSchemaToStorageTableSchema(queryStatsBigQueryTableSchema)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	md, err := adapt.StorageSchemaToProto2Descriptor(storageSchema, "QueryStat")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	messageDescriptor, ok := md.(protoreflect.MessageDescriptor)
+	if !ok {
+		return errors.New("adapted descriptor is not a MessageDescriptor")
+	}
+	descriptorProto, err := adapt.NormalizeDescriptor(messageDescriptor)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	managedStream, err := client.NewManagedStream(ctx,
+		managedwriter.WithDestinationTable(
+			managedwriter.TableParentFromParts(dataset.ProjectID, dataset.DatasetID, table),
+		),
+		managedwriter.WithType(managedwriter.DefaultStream),
+		managedwriter.WithSchemaDescriptor(descriptorProto),
+	)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer managedStream.Close()
+
+	rows := make([][]byte, 0, len(qss))
+	for _, qs := range qss {
+		b, err := proto.Marshal(qs.toDynamicMessage(messageDescriptor))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		rows = append(rows, b)
+	}
+
+	result, err := managedStream.AppendRows(ctx, rows)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := result.GetResult(ctx); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// toDynamicMessage is QueryStatの値を、queryStatsBigQueryTableSchemaから生成したMessageDescriptorに詰め直す
+// フィールド名はqueryStatsBigQueryTableSchemaのNameとQueryStatのフィールド名を合わせてあるので、そのまま対応させることができる
+func (s *QueryStat) toDynamicMessage(md protoreflect.MessageDescriptor) *dynamicpb.Message {
+	m := dynamicpb.NewMessage(md)
+
+	set := func(name string, v interface{}) {
+		fd := md.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return
+		}
+		m.Set(fd, protoreflect.ValueOf(v))
+	}
+
+	set("IntervalEnd", s.IntervalEnd.UnixMicro())
+	set("Text", s.Text)
+	set("TextTruncated", s.TextTruncated)
+	set("TextFingerprint", s.TextFingerprint)
+	set("ExecuteCount", s.ExecuteCount)
+	set("AvgLatencySeconds", s.AvgLatencySeconds)
+	set("AvgRows", s.AvgRows)
+	set("AvgBytes", s.AvgBytes)
+	set("AvgRowsScanned", s.AvgRowsScanned)
+	set("AvgCPUSeconds", s.AvgCPUSeconds)
+
+	return m
+}
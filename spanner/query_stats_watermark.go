@@ -0,0 +1,129 @@
+package spanner
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"cloud.google.com/go/storage"
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+)
+
+// WatermarkStore is QueryStatsCopyRunnerがBigQueryへのコピー済みのinterval_endを永続化するためのインターフェイス
+type WatermarkStore interface {
+	// Get is keyに対応するwatermarkを取得する。まだ保存されていない場合は、time.Time{} (ゼロ値) を返す
+	Get(ctx context.Context, key string) (time.Time, error)
+	// Set is keyに対応するwatermarkを保存する
+	Set(ctx context.Context, key string, watermark time.Time) error
+}
+
+// GCSWatermarkStore is GCS Object をwatermarkの永続化先に使うWatermarkStore
+// keyごとに bucket/prefix/key というObjectにRFC3339形式のタイムスタンプを保存する
+type GCSWatermarkStore struct {
+	client *storage.Client
+	bucket string
+	prefix string
+}
+
+// NewGCSWatermarkStore is GCSWatermarkStoreを作成する
+func NewGCSWatermarkStore(client *storage.Client, bucket string, prefix string) *GCSWatermarkStore {
+	return &GCSWatermarkStore{
+		client: client,
+		bucket: bucket,
+		prefix: prefix,
+	}
+}
+
+func (s *GCSWatermarkStore) objectName(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return s.prefix + "/" + key
+}
+
+// Get is GCS ObjectからWatermarkを取得する。Objectが存在しない場合は、time.Time{} (ゼロ値) を返す
+func (s *GCSWatermarkStore) Get(ctx context.Context, key string) (time.Time, error) {
+	r, err := s.client.Bucket(s.bucket).Object(s.objectName(key)).NewReader(ctx)
+	if err == storage.ErrObjectNotExist {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, errors.WithStack(err)
+	}
+	defer r.Close()
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return time.Time{}, errors.WithStack(err)
+	}
+
+	watermark, err := time.Parse(time.RFC3339Nano, string(b))
+	if err != nil {
+		return time.Time{}, errors.WithStack(err)
+	}
+	return watermark, nil
+}
+
+// Set is WatermarkをGCS Objectに保存する
+func (s *GCSWatermarkStore) Set(ctx context.Context, key string, watermark time.Time) error {
+	w := s.client.Bucket(s.bucket).Object(s.objectName(key)).NewWriter(ctx)
+	if _, err := w.Write([]byte(watermark.Format(time.RFC3339Nano))); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := w.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// SpannerWatermarkStoreTable is SpannerWatermarkStoreがデフォルトで利用するTable名
+// Key STRING(MAX), Watermark TIMESTAMP の2つのColumnを持つTableをあらかじめ作成しておく必要がある
+const SpannerWatermarkStoreTable = "QueryStatsWatermark"
+
+// SpannerWatermarkStore is Spanner Tableをwatermarkの永続化先に使うWatermarkStore
+type SpannerWatermarkStore struct {
+	client *spanner.Client
+	table  string
+}
+
+// NewSpannerWatermarkStore is SpannerWatermarkStoreを作成する
+func NewSpannerWatermarkStore(client *spanner.Client, table string) *SpannerWatermarkStore {
+	if table == "" {
+		table = SpannerWatermarkStoreTable
+	}
+	return &SpannerWatermarkStore{
+		client: client,
+		table:  table,
+	}
+}
+
+// Get is Spanner Tableからwatermarkを取得する。該当するRowが存在しない場合は、time.Time{} (ゼロ値) を返す
+func (s *SpannerWatermarkStore) Get(ctx context.Context, key string) (time.Time, error) {
+	iter := s.client.Single().Read(ctx, s.table, spanner.Key{key}, []string{"Watermark"})
+	defer iter.Stop()
+
+	row, err := iter.Next()
+	if err == iterator.Done {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, errors.WithStack(err)
+	}
+
+	var watermark time.Time
+	if err := row.Column(0, &watermark); err != nil {
+		return time.Time{}, errors.WithStack(err)
+	}
+	return watermark, nil
+}
+
+// Set is Spanner Tableにwatermarkを保存する
+func (s *SpannerWatermarkStore) Set(ctx context.Context, key string, watermark time.Time) error {
+	m := spanner.InsertOrUpdate(s.table, []string{"Key", "Watermark"}, []interface{}{key, watermark})
+	if _, err := s.client.Apply(ctx, []*spanner.Mutation{m}); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package spanner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/spanner"
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+)
+
+const readStatsTopMinute = `
+SELECT
+  text,
+  text_truncated,
+  text_fingerprint,
+  interval_end,
+  execution_count,
+  avg_latency_seconds,
+  avg_rows,
+  avg_bytes,
+  avg_rows_scanned,
+  avg_cpu_seconds,
+  avg_locking_delay_seconds,
+  avg_client_wait_seconds,
+  avg_leader_refresh_delay_seconds
+FROM {{.Table}}
+`
+
+const (
+	readStatsTopMinuteTable   = "spanner_sys.read_stats_top_minute"
+	readStatsTop10MinuteTable = "spanner_sys.read_stats_top_10minute"
+	readStatsTopHourTable     = "spanner_sys.read_stats_top_hour"
+)
+
+type ReadStatsParam struct {
+	Table string
+}
+
+// ReadStatsCopyService is spanner_sys.read_stats_top_*をBigQueryにコピーする
+type ReadStatsCopyService struct {
+	readStatsTopQueryTemplate *template.Template
+	spanner                   *spanner.Client
+	bq                        *bigquery.Client
+}
+
+func NewReadStatsCopyService(ctx context.Context, spannerClient *spanner.Client, bqClient *bigquery.Client) (*ReadStatsCopyService, error) {
+	tmpl, err := template.New("getReadStatsTopQuery").Parse(readStatsTopMinute)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReadStatsCopyService{
+		readStatsTopQueryTemplate: tmpl,
+		spanner:                   spannerClient,
+		bq:                        bqClient,
+	}, nil
+}
+
+type ReadStat struct {
+	InsertID                     string
+	IntervalEnd                  time.Time `spanner:"interval_end"`                     // End of the time interval that the included reads occurred in.
+	Text                         string    `spanner:"text"`                             // Read shape, in a SQL-like syntax, truncated to approximately 64KB.
+	TextTruncated                bool      `spanner:"text_truncated"`                   // Whether or not the read shape text was truncated.
+	TextFingerprint              int64     `spanner:"text_fingerprint"`                 // Hash of the read shape text.
+	ExecuteCount                 int64     `spanner:"execution_count"`                  // Number of times Cloud Spanner saw the read shape during the interval.
+	AvgLatencySeconds            float64   `spanner:"avg_latency_seconds"`              // Average length of time, in seconds, for each read.
+	AvgRows                      float64   `spanner:"avg_rows"`                         // Average number of rows that the read returned.
+	AvgBytes                     float64   `spanner:"avg_bytes"`                        // Average number of data bytes that the read returned.
+	AvgRowsScanned               float64   `spanner:"avg_rows_scanned"`                 // Average number of rows that the read scanned.
+	AvgCPUSeconds                float64   `spanner:"avg_cpu_seconds"`                  // Average number of seconds of CPU time Cloud Spanner spent on the read.
+	AvgLockingDelaySeconds       float64   `spanner:"avg_locking_delay_seconds"`        // Average length of time, in seconds, the read spent waiting on locks.
+	AvgClientWaitSeconds         float64   `spanner:"avg_client_wait_seconds"`          // Average length of time, in seconds, Cloud Spanner spent waiting for the client to accept the response.
+	AvgLeaderRefreshDelaySeconds float64   `spanner:"avg_leader_refresh_delay_seconds"` // Average length of time, in seconds, the read spent waiting to refresh the leader.
+}
+
+func (s *ReadStat) ToInsertID() string {
+	s.InsertID = fmt.Sprintf("%v-_-%v", s.IntervalEnd.Unix(), s.TextFingerprint)
+	return s.InsertID
+}
+
+func (s *ReadStatsCopyService) GetReadStats(ctx context.Context, table string) ([]*ReadStat, error) {
+	var tpl bytes.Buffer
+	if err := s.readStatsTopQueryTemplate.Execute(&tpl, ReadStatsParam{Table: table}); err != nil {
+		return nil, err
+	}
+	iter := s.spanner.Single().Query(ctx, spanner.NewStatement(tpl.String()))
+	defer iter.Stop()
+
+	rets := []*ReadStat{}
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		var result ReadStat
+		if err := row.ToStruct(&result); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		rets = append(rets, &result)
+	}
+
+	return rets, nil
+}
+
+var readStatsBigQueryTableSchema = bigquery.Schema{
+	{Name: "IntervalEnd", Required: true, Type: bigquery.TimestampFieldType},
+	{Name: "Text", Required: true, Type: bigquery.StringFieldType},
+	{Name: "TextTruncated", Required: true, Type: bigquery.BooleanFieldType},
+	{Name: "TextFingerprint", Required: true, Type: bigquery.IntegerFieldType},
+	{Name: "ExecuteCount", Required: true, Type: bigquery.IntegerFieldType},
+	{Name: "AvgLatencySeconds", Required: true, Type: bigquery.FloatFieldType},
+	{Name: "AvgRows", Required: true, Type: bigquery.FloatFieldType},
+	{Name: "AvgBytes", Required: true, Type: bigquery.FloatFieldType},
+	{Name: "AvgRowsScanned", Required: true, Type: bigquery.FloatFieldType},
+	{Name: "AvgCPUSeconds", Required: true, Type: bigquery.FloatFieldType},
+	{Name: "AvgLockingDelaySeconds", Required: true, Type: bigquery.FloatFieldType},
+	{Name: "AvgClientWaitSeconds", Required: true, Type: bigquery.FloatFieldType},
+	{Name: "AvgLeaderRefreshDelaySeconds", Required: true, Type: bigquery.FloatFieldType},
+}
+
+func (s *ReadStatsCopyService) ToBigQuery(ctx context.Context, dataset *bigquery.Dataset, table string, rss []*ReadStat) error {
+	var sss []*bigquery.StructSaver
+	for _, rs := range rss {
+		insertID := rs.ToInsertID()
+		sss = append(sss, &bigquery.StructSaver{
+			Schema:   readStatsBigQueryTableSchema,
+			InsertID: insertID,
+			Struct:   rs,
+		})
+	}
+
+	if err := s.bq.DatasetInProject(dataset.ProjectID, dataset.DatasetID).Table(table).Inserter().Put(ctx, sss); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package spanner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/spanner"
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+)
+
+const txnStatsTopMinute = `
+SELECT
+  fprint,
+  read_columns,
+  read_tables,
+  write_constructive_columns,
+  write_delete_tables,
+  write_tables,
+  priority,
+  interval_end,
+  avg_participants,
+  avg_total_latency_seconds,
+  commit_attempt_count,
+  commit_abort_count,
+  commit_retry_count,
+  commit_failed_precondition_count
+FROM {{.Table}}
+`
+
+const (
+	txnStatsTopMinuteTable   = "spanner_sys.txn_stats_top_minute"
+	txnStatsTop10MinuteTable = "spanner_sys.txn_stats_top_10minute"
+	txnStatsTopHourTable     = "spanner_sys.txn_stats_top_hour"
+)
+
+type TxnStatsParam struct {
+	Table string
+}
+
+// TxnStatsCopyService is spanner_sys.txn_stats_top_*をBigQueryにコピーする
+type TxnStatsCopyService struct {
+	txnStatsTopQueryTemplate *template.Template
+	spanner                  *spanner.Client
+	bq                       *bigquery.Client
+}
+
+func NewTxnStatsCopyService(ctx context.Context, spannerClient *spanner.Client, bqClient *bigquery.Client) (*TxnStatsCopyService, error) {
+	tmpl, err := template.New("getTxnStatsTopQuery").Parse(txnStatsTopMinute)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TxnStatsCopyService{
+		txnStatsTopQueryTemplate: tmpl,
+		spanner:                  spannerClient,
+		bq:                       bqClient,
+	}, nil
+}
+
+type TxnStat struct {
+	InsertID                      string
+	IntervalEnd                   time.Time `spanner:"interval_end"`                     // End of the time interval that the included transactions occurred in.
+	Fingerprint                   int64     `spanner:"fprint"`                           // Hash of the transaction's read and write shape.
+	ReadColumns                   []string  `spanner:"read_columns"`                     // Columns read by the transaction.
+	ReadTables                    []string  `spanner:"read_tables"`                      // Tables read by the transaction.
+	WriteConstructiveColumns      []string  `spanner:"write_constructive_columns"`       // Columns inserted or updated by the transaction.
+	WriteDeleteTables             []string  `spanner:"write_delete_tables"`              // Tables that had rows deleted by the transaction.
+	WriteTables                   []string  `spanner:"write_tables"`                     // Tables written by the transaction.
+	Priority                      string    `spanner:"priority"`                         // Priority of the transaction.
+	AvgParticipants               float64   `spanner:"avg_participants"`                 // Average number of participants involved in the transaction.
+	AvgTotalLatencySeconds        float64   `spanner:"avg_total_latency_seconds"`        // Average length of time, in seconds, for the transaction to commit or abort, including retries.
+	CommitAttemptCount            int64     `spanner:"commit_attempt_count"`             // Number of times Cloud Spanner saw the transaction attempt to commit.
+	CommitAbortCount              int64     `spanner:"commit_abort_count"`               // Number of times the transaction aborted.
+	CommitRetryCount              int64     `spanner:"commit_retry_count"`               // Number of times the transaction was retried after aborting.
+	CommitFailedPreconditionCount int64     `spanner:"commit_failed_precondition_count"` // Number of times the transaction failed with a FAILED_PRECONDITION error.
+}
+
+func (s *TxnStat) ToInsertID() string {
+	s.InsertID = fmt.Sprintf("%v-_-%v", s.IntervalEnd.Unix(), s.Fingerprint)
+	return s.InsertID
+}
+
+func (s *TxnStatsCopyService) GetTxnStats(ctx context.Context, table string) ([]*TxnStat, error) {
+	var tpl bytes.Buffer
+	if err := s.txnStatsTopQueryTemplate.Execute(&tpl, TxnStatsParam{Table: table}); err != nil {
+		return nil, err
+	}
+	iter := s.spanner.Single().Query(ctx, spanner.NewStatement(tpl.String()))
+	defer iter.Stop()
+
+	rets := []*TxnStat{}
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		var result TxnStat
+		if err := row.ToStruct(&result); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		rets = append(rets, &result)
+	}
+
+	return rets, nil
+}
+
+var txnStatsBigQueryTableSchema = bigquery.Schema{
+	{Name: "IntervalEnd", Required: true, Type: bigquery.TimestampFieldType},
+	{Name: "Fingerprint", Required: true, Type: bigquery.IntegerFieldType},
+	{Name: "ReadColumns", Required: false, Repeated: true, Type: bigquery.StringFieldType},
+	{Name: "ReadTables", Required: false, Repeated: true, Type: bigquery.StringFieldType},
+	{Name: "WriteConstructiveColumns", Required: false, Repeated: true, Type: bigquery.StringFieldType},
+	{Name: "WriteDeleteTables", Required: false, Repeated: true, Type: bigquery.StringFieldType},
+	{Name: "WriteTables", Required: false, Repeated: true, Type: bigquery.StringFieldType},
+	{Name: "Priority", Required: true, Type: bigquery.StringFieldType},
+	{Name: "AvgParticipants", Required: true, Type: bigquery.FloatFieldType},
+	{Name: "AvgTotalLatencySeconds", Required: true, Type: bigquery.FloatFieldType},
+	{Name: "CommitAttemptCount", Required: true, Type: bigquery.IntegerFieldType},
+	{Name: "CommitAbortCount", Required: true, Type: bigquery.IntegerFieldType},
+	{Name: "CommitRetryCount", Required: true, Type: bigquery.IntegerFieldType},
+	{Name: "CommitFailedPreconditionCount", Required: true, Type: bigquery.IntegerFieldType},
+}
+
+func (s *TxnStatsCopyService) ToBigQuery(ctx context.Context, dataset *bigquery.Dataset, table string, tss []*TxnStat) error {
+	var sss []*bigquery.StructSaver
+	for _, ts := range tss {
+		insertID := ts.ToInsertID()
+		sss = append(sss, &bigquery.StructSaver{
+			Schema:   txnStatsBigQueryTableSchema,
+			InsertID: insertID,
+			Struct:   ts,
+		})
+	}
+
+	if err := s.bq.DatasetInProject(dataset.ProjectID, dataset.DatasetID).Table(table).Inserter().Put(ctx, sss); err != nil {
+		return err
+	}
+	return nil
+}